@@ -0,0 +1,92 @@
+package intercom
+
+// proto.Audio has no RoomID/SenderID/Seq fields of its own (that
+// message is generated from intercom.proto, which this checkout
+// doesn't have a copy of to edit), so every outgoing buffer is
+// prefixed with a small envelope ahead of the real audio samples,
+// mirroring the [room][sender] envelope encodeImageFrame uses for
+// video:
+//
+//	[roomID length][roomID bytes][senderID length][senderID bytes][seq][samples]
+//
+// Each length/byte/seq value is carried as its own int32 element
+// since Samples is an []int32, not a []byte. decodeAudioSamples's
+// roomID lets handleGrpcStreamRec drop audio from other rooms
+// client-side (see the note on encodeImageFrame), and senderID lets it
+// route samples to the right Participant's jitter buffer instead of
+// every sender colliding in one shared sequence space. Promote these
+// to real proto fields, and timing to a real wire timestamp, once
+// intercom.proto is available to edit.
+
+// encodeAudioSamples prefixes samples with the room id, sender id, and
+// seq for transmission.
+func encodeAudioSamples(roomID, senderID string, seq uint32, samples []int32) []int32 {
+	out := make([]int32, 0, len(roomID)+len(senderID)+3+len(samples))
+	out = append(out, int32(len(roomID)))
+	for _, b := range []byte(roomID) {
+		out = append(out, int32(b))
+	}
+	out = append(out, int32(len(senderID)))
+	for _, b := range []byte(senderID) {
+		out = append(out, int32(b))
+	}
+	out = append(out, int32(seq))
+	out = append(out, samples...)
+	return out
+}
+
+// decodeAudioSamples reverses encodeAudioSamples.
+func decodeAudioSamples(raw []int32) (roomID, senderID string, seq uint32, samples []int32, ok bool) {
+	pos := 0
+
+	roomIDLen, ok := readInt32Len(raw, pos)
+	if !ok {
+		return "", "", 0, nil, false
+	}
+	pos++
+	roomBytes, ok := readInt32String(raw, pos, roomIDLen)
+	if !ok {
+		return "", "", 0, nil, false
+	}
+	pos += roomIDLen
+
+	senderIDLen, ok := readInt32Len(raw, pos)
+	if !ok {
+		return "", "", 0, nil, false
+	}
+	pos++
+	senderBytes, ok := readInt32String(raw, pos, senderIDLen)
+	if !ok {
+		return "", "", 0, nil, false
+	}
+	pos += senderIDLen
+
+	if pos >= len(raw) {
+		return "", "", 0, nil, false
+	}
+	seq = uint32(raw[pos])
+	pos++
+
+	return roomBytes, senderBytes, seq, raw[pos:], true
+}
+
+// readInt32Len reads the length-prefix element at pos.
+func readInt32Len(raw []int32, pos int) (int, bool) {
+	if pos >= len(raw) {
+		return 0, false
+	}
+	return int(raw[pos]), true
+}
+
+// readInt32String reads n elements starting at pos as a string, one
+// byte per int32 element.
+func readInt32String(raw []int32, pos, n int) (string, bool) {
+	if n < 0 || pos+n > len(raw) {
+		return "", false
+	}
+	b := make([]byte, n)
+	for i := 0; i < n; i++ {
+		b[i] = byte(raw[pos+i])
+	}
+	return string(b), true
+}
@@ -0,0 +1,59 @@
+package intercom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeAudioSamplesRoundTrip(t *testing.T) {
+	samples := []int32{1, -2, 3, 0, 42}
+
+	raw := encodeAudioSamples("room-a", "alice", 7, samples)
+
+	roomID, senderID, seq, got, ok := decodeAudioSamples(raw)
+	if !ok {
+		t.Fatalf("decodeAudioSamples: ok = false, want true")
+	}
+	if roomID != "room-a" {
+		t.Errorf("roomID = %q, want %q", roomID, "room-a")
+	}
+	if senderID != "alice" {
+		t.Errorf("senderID = %q, want %q", senderID, "alice")
+	}
+	if seq != 7 {
+		t.Errorf("seq = %d, want 7", seq)
+	}
+	if !reflect.DeepEqual(got, samples) {
+		t.Errorf("samples = %v, want %v", got, samples)
+	}
+}
+
+func TestEncodeDecodeAudioSamplesEmptyIDs(t *testing.T) {
+	raw := encodeAudioSamples("", "", 0, []int32{9})
+
+	roomID, senderID, seq, samples, ok := decodeAudioSamples(raw)
+	if !ok || roomID != "" || senderID != "" || seq != 0 || !reflect.DeepEqual(samples, []int32{9}) {
+		t.Fatalf("decodeAudioSamples(%v) = (%q, %q, %d, %v, %v)", raw, roomID, senderID, seq, samples, ok)
+	}
+}
+
+func TestDecodeAudioSamplesTruncated(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  []int32
+	}{
+		{"empty", nil},
+		{"missing room bytes", []int32{5}},
+		{"missing sender length", []int32{0}},
+		{"missing sender bytes", []int32{0, 3}},
+		{"missing seq", []int32{0, 0}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, _, _, ok := decodeAudioSamples(tc.raw); ok {
+				t.Errorf("decodeAudioSamples(%v): ok = true, want false", tc.raw)
+			}
+		})
+	}
+}
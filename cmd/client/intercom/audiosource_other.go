@@ -0,0 +1,12 @@
+//go:build !windows
+
+package intercom
+
+import "fmt"
+
+// newLoopbackAudioSource is only implemented on Windows (WASAPI
+// loopback). Elsewhere AudioSourceLoopback/AudioSourceMix report an
+// error instead of silently broadcasting the microphone.
+func newLoopbackAudioSource(framesPerBuffer int) (AudioSource, error) {
+	return nil, fmt.Errorf("loopback audio capture is only supported on windows")
+}
@@ -0,0 +1,28 @@
+package intercom
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClipInt32(t *testing.T) {
+	cases := []struct {
+		name string
+		in   int64
+		want int32
+	}{
+		{"within range", 100, 100},
+		{"exactly max", math.MaxInt32, math.MaxInt32},
+		{"exactly min", math.MinInt32, math.MinInt32},
+		{"overflows max", math.MaxInt32 + 1000, math.MaxInt32},
+		{"overflows min", math.MinInt32 - 1000, math.MinInt32},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := clipInt32(tc.in); got != tc.want {
+				t.Errorf("clipInt32(%d) = %d, want %d", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+//go:build windows
+
+package intercom
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"unsafe"
+
+	"github.com/go-ole/go-ole"
+	wca "github.com/moutend/go-wca"
+)
+
+// loopbackSampleRate/loopbackChannels describe the shared-mode format
+// WASAPI loopback activates with on most Windows render endpoints.
+const (
+	loopbackSampleRate = 48000
+	loopbackChannels   = 2
+)
+
+// wasapiLoopbackAudioSource captures the default render endpoint's
+// output (system/speaker audio) via WASAPI loopback mode, converting
+// its 48kHz float32 stereo frames into the module's sampleRate (44100)
+// int32 mono samples so they can feed the same proto.Audio broadcast
+// path as the microphone.
+type wasapiLoopbackAudioSource struct {
+	framesPerBuffer int
+
+	mu            sync.Mutex
+	audioClient   *wca.IAudioClient
+	captureClient *wca.IAudioCaptureClient
+	eventHandle   uintptr
+}
+
+func newLoopbackAudioSource(framesPerBuffer int) (AudioSource, error) {
+	return &wasapiLoopbackAudioSource{framesPerBuffer: framesPerBuffer}, nil
+}
+
+func (w *wasapiLoopbackAudioSource) Open() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED); err != nil {
+		return fmt.Errorf("CoInitializeEx: %w", err)
+	}
+
+	var enumerator *wca.IMMDeviceEnumerator
+	if err := wca.CoCreateInstance(wca.CLSID_MMDeviceEnumerator, 0, wca.CLSCTX_ALL, wca.IID_IMMDeviceEnumerator, &enumerator); err != nil {
+		return fmt.Errorf("creating device enumerator: %w", err)
+	}
+	defer enumerator.Release()
+
+	var device *wca.IMMDevice
+	if err := enumerator.GetDefaultAudioEndpoint(wca.ERender, wca.EConsole, &device); err != nil {
+		return fmt.Errorf("getting default render endpoint: %w", err)
+	}
+	defer device.Release()
+
+	var audioClient *wca.IAudioClient
+	if err := device.Activate(wca.IID_IAudioClient, wca.CLSCTX_ALL, nil, &audioClient); err != nil {
+		return fmt.Errorf("activating audio client: %w", err)
+	}
+
+	waveFormat := &wca.WAVEFORMATEX{
+		WFormatTag:      wca.WAVE_FORMAT_IEEE_FLOAT,
+		NChannels:       loopbackChannels,
+		NSamplesPerSec:  loopbackSampleRate,
+		WBitsPerSample:  32,
+		NBlockAlign:     loopbackChannels * 32 / 8,
+		NAvgBytesPerSec: loopbackSampleRate * loopbackChannels * 32 / 8,
+	}
+
+	if err := audioClient.Initialize(wca.AUDCLNT_SHAREMODE_SHARED, wca.AUDCLNT_STREAMFLAGS_LOOPBACK|wca.AUDCLNT_STREAMFLAGS_EVENTCALLBACK, 0, 0, waveFormat, nil); err != nil {
+		return fmt.Errorf("initializing loopback client: %w", err)
+	}
+
+	eventHandle, err := wca.CreateEventExA(0, 0, 0, wca.EVENT_ALL_ACCESS)
+	if err != nil {
+		return fmt.Errorf("creating audio event: %w", err)
+	}
+	if err := audioClient.SetEventHandle(eventHandle); err != nil {
+		return fmt.Errorf("setting audio event handle: %w", err)
+	}
+
+	var captureClient *wca.IAudioCaptureClient
+	if err := audioClient.GetService(wca.IID_IAudioCaptureClient, &captureClient); err != nil {
+		return fmt.Errorf("getting capture client: %w", err)
+	}
+
+	if err := audioClient.Start(); err != nil {
+		return fmt.Errorf("starting loopback capture: %w", err)
+	}
+
+	w.audioClient = audioClient
+	w.captureClient = captureClient
+	w.eventHandle = eventHandle
+	return nil
+}
+
+// Read blocks for the next loopback event, pulls every available
+// buffer from WASAPI, downmixes 48kHz stereo float32 to 44100 mono
+// int32, and returns exactly one framesPerBuffer-sized slice.
+func (w *wasapiLoopbackAudioSource) Read() ([]int32, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := wca.WaitForSingleObject(w.eventHandle, wca.INFINITE); err != nil {
+		return nil, fmt.Errorf("waiting for loopback event: %w", err)
+	}
+
+	var packetLength uint32
+	if err := w.captureClient.GetNextPacketSize(&packetLength); err != nil {
+		return nil, fmt.Errorf("getting packet size: %w", err)
+	}
+
+	var stereo []float32
+	for packetLength != 0 {
+		var data *byte
+		var numFrames uint32
+		var flags uint32
+
+		if err := w.captureClient.GetBuffer(&data, &numFrames, &flags, nil, nil); err != nil {
+			return nil, fmt.Errorf("getting capture buffer: %w", err)
+		}
+
+		frames := unsafe.Slice((*float32)(unsafe.Pointer(data)), int(numFrames)*loopbackChannels)
+		stereo = append(stereo, frames...)
+
+		if err := w.captureClient.ReleaseBuffer(numFrames); err != nil {
+			return nil, fmt.Errorf("releasing capture buffer: %w", err)
+		}
+		if err := w.captureClient.GetNextPacketSize(&packetLength); err != nil {
+			return nil, fmt.Errorf("getting packet size: %w", err)
+		}
+	}
+
+	return resampleStereoFloatToMonoInt32(stereo, loopbackSampleRate, sampleRate, w.framesPerBuffer), nil
+}
+
+func (w *wasapiLoopbackAudioSource) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.audioClient != nil {
+		w.audioClient.Stop()
+		w.audioClient.Release()
+	}
+	if w.captureClient != nil {
+		w.captureClient.Release()
+	}
+	ole.CoUninitialize()
+	return nil
+}
+
+// resampleStereoFloatToMonoInt32 downmixes interleaved stereo float32
+// samples at fromRate to mono int32 samples at toRate via nearest-
+// neighbor resampling, returning exactly wantFrames samples (zero-
+// padded if fewer were captured).
+func resampleStereoFloatToMonoInt32(stereo []float32, fromRate, toRate, wantFrames int) []int32 {
+	out := make([]int32, wantFrames)
+	frameCount := len(stereo) / loopbackChannels
+	if frameCount == 0 {
+		return out
+	}
+
+	ratio := float64(fromRate) / float64(toRate)
+	for i := 0; i < wantFrames; i++ {
+		srcFrame := int(float64(i) * ratio)
+		if srcFrame >= frameCount {
+			break
+		}
+		left := stereo[srcFrame*loopbackChannels]
+		right := stereo[srcFrame*loopbackChannels+1]
+		mono := (left + right) / 2
+		out[i] = int32(mono * math.MaxInt32)
+	}
+	return out
+}
@@ -0,0 +1,265 @@
+package intercom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os/exec"
+
+	"github.com/3xcellent/intercom/proto"
+	"gocv.io/x/gocv"
+)
+
+// Codec identifiers carried in proto.Image.Type. A Type that is not one
+// of the codec ids below (codecRaw included) is read as a raw
+// gocv.MatType: with no FrameCodec configured, Type holds the frame's
+// MatType and the payload after the envelope described below is the
+// uncompressed frame.
+//
+// proto.Image has no Codec/IsKeyframe/SenderID/RoomID fields of its
+// own (that message is generated from intercom.proto, which this
+// checkout doesn't have a copy of to edit), so every frame's Bytes are
+// prefixed with a small envelope ahead of the payload:
+//
+//	[keyframe byte][roomID length byte][roomID bytes][senderID length byte][senderID bytes][payload]
+//
+// decodeImageFrame/processBroadcastImage use the roomID to drop frames
+// from other rooms client-side, since there's no server-side
+// roomManager in this checkout to keep rooms from sharing one global
+// fan-out in the first place. Once proto.Image carries these as real
+// fields (and a server exists here to route on them), this envelope
+// goes away and demuxing by sender/room becomes a direct field read.
+const (
+	codecRaw  int32 = 0
+	codecJPEG int32 = 1000
+	codecVP8  int32 = 1001
+	codecH264 int32 = 1002
+)
+
+// FrameCodec compresses/decompresses the frames sendVideoCapture
+// captures and processBroadcastImage receives, replacing the raw
+// ~1.4MB/frame BGR payload the uncompressed path sends.
+type FrameCodec interface {
+	// CodecID identifies this codec in proto.Image.Type.
+	CodecID() int32
+	// Encode compresses img, reporting whether the result can be
+	// decoded on its own (a keyframe) or depends on prior frames.
+	Encode(img gocv.Mat) (data []byte, keyframe bool, err error)
+	// Decode reverses Encode, producing a BGR gocv.Mat. Callers must
+	// Close the returned Mat.
+	Decode(data []byte) (gocv.Mat, error)
+}
+
+func codecByID(id int32) (FrameCodec, bool) {
+	switch id {
+	case codecJPEG:
+		return jpegCodec{}, true
+	case codecVP8:
+		return vp8Codec{}, true
+	case codecH264:
+		return h264Codec{}, true
+	default:
+		return nil, false
+	}
+}
+
+// encodeImageFrame runs codec over img (when non-nil), tags the result
+// with roomID/senderID, and returns the proto.Image.Type/Bytes pair
+// sendVideoCapture should send. A nil codec preserves the original
+// raw-BGR wire format (still wrapped in the room/sender envelope).
+func encodeImageFrame(codec FrameCodec, roomID, senderID string, img gocv.Mat) (codecType int32, payload []byte, err error) {
+	var encoded []byte
+	keyframe := true
+
+	if codec == nil {
+		codecType = int32(img.Type())
+		encoded = img.ToBytes()
+	} else {
+		encoded, keyframe, err = codec.Encode(img)
+		if err != nil {
+			return 0, nil, fmt.Errorf("encoding frame: %w", err)
+		}
+		codecType = codec.CodecID()
+	}
+
+	header := byte(0)
+	if keyframe {
+		header = 1
+	}
+
+	envelope := make([]byte, 0, 3+len(roomID)+len(senderID)+len(encoded))
+	envelope = append(envelope, header, byte(len(roomID)))
+	envelope = append(envelope, roomID...)
+	envelope = append(envelope, byte(len(senderID)))
+	envelope = append(envelope, senderID...)
+	envelope = append(envelope, encoded...)
+	return codecType, envelope, nil
+}
+
+// isKeyframeImage reports whether img is a keyframe without decoding
+// it, so handleGrpcStreamRec can drop non-keyframes until the first
+// keyframe arrives after a (re)connect.
+func isKeyframeImage(img proto.Image) bool {
+	return len(img.Bytes) > 0 && img.Bytes[0] == 1
+}
+
+// decodeImageFrame reverses encodeImageFrame: codecType/payload come
+// straight off the wire (proto.Image.Type/Bytes). Returns the sending
+// room/participant ids, the decoded BGR Mat (caller must Close it), and
+// whether this was a keyframe.
+func decodeImageFrame(codecType int32, payload []byte, height, width int) (roomID, senderID string, mat gocv.Mat, keyframe bool, err error) {
+	if len(payload) < 2 {
+		return "", "", gocv.Mat{}, false, fmt.Errorf("frame envelope too short: %d bytes", len(payload))
+	}
+
+	keyframe = payload[0] == 1
+	pos := 1
+
+	roomIDLen := int(payload[pos])
+	pos++
+	if len(payload) < pos+roomIDLen+1 {
+		return "", "", gocv.Mat{}, false, fmt.Errorf("frame envelope truncated room id")
+	}
+	roomID = string(payload[pos : pos+roomIDLen])
+	pos += roomIDLen
+
+	senderIDLen := int(payload[pos])
+	pos++
+	if len(payload) < pos+senderIDLen {
+		return "", "", gocv.Mat{}, false, fmt.Errorf("frame envelope truncated sender id")
+	}
+	senderID = string(payload[pos : pos+senderIDLen])
+	pos += senderIDLen
+	encoded := payload[pos:]
+
+	codec, ok := codecByID(codecType)
+	if !ok {
+		mat, err = gocv.NewMatFromBytes(height, width, gocv.MatType(codecType), encoded)
+		return roomID, senderID, mat, keyframe, err
+	}
+
+	mat, err = codec.Decode(encoded)
+	return roomID, senderID, mat, keyframe, err
+}
+
+// jpegCodec encodes frames as JPEG via gocv.IMEncode. Every JPEG frame
+// stands alone, so it's always a keyframe.
+type jpegCodec struct{}
+
+func (jpegCodec) CodecID() int32 { return codecJPEG }
+
+func (jpegCodec) Encode(img gocv.Mat) ([]byte, bool, error) {
+	buf, err := gocv.IMEncode(gocv.JPEGFileExt, img)
+	if err != nil {
+		return nil, false, err
+	}
+	defer buf.Close()
+	return buf.GetBytes(), true, nil
+}
+
+func (jpegCodec) Decode(data []byte) (gocv.Mat, error) {
+	return gocv.IMDecode(data, gocv.IMReadColor)
+}
+
+// vp8Codec and h264Codec encode/decode by shelling out to a per-frame
+// `ffmpeg` subprocess instead of binding to libvpx/x264 natively (the
+// same no-cgo, no-vendored-dependency technique ffmpegPipeline and
+// rtspClient use). That makes them genuinely usable without adding a
+// Go dependency, at the cost of spawning a process per frame — fine
+// for this client's low frame rate, not something to scale to a
+// real-time server path. Every encode is intra-only (ffmpeg only ever
+// sees one frame, so it can't reference a previous one), so every
+// frame is a keyframe, same as jpegCodec.
+//
+// Decode's signature has no width/height parameter, so Encode prepends
+// the source frame's dimensions as an 8-byte big-endian header ahead
+// of the ffmpeg container bytes; encodeWithFfmpeg/decodeWithFfmpeg
+// below share that framing.
+type vp8Codec struct{}
+
+func (vp8Codec) CodecID() int32 { return codecVP8 }
+
+func (vp8Codec) Encode(img gocv.Mat) ([]byte, bool, error) {
+	return encodeWithFfmpeg(img, "libvpx", "ivf")
+}
+
+func (vp8Codec) Decode(data []byte) (gocv.Mat, error) {
+	return decodeWithFfmpeg(data, "ivf")
+}
+
+type h264Codec struct{}
+
+func (h264Codec) CodecID() int32 { return codecH264 }
+
+func (h264Codec) Encode(img gocv.Mat) ([]byte, bool, error) {
+	return encodeWithFfmpeg(img, "libx264", "h264")
+}
+
+func (h264Codec) Decode(data []byte) (gocv.Mat, error) {
+	return decodeWithFfmpeg(data, "h264")
+}
+
+// encodeWithFfmpeg pipes img's raw BGR24 bytes into `ffmpeg ... -c:v
+// videoCodec -f containerFormat -`, capturing the encoded container on
+// stdout and prefixing it with img's width/height for decodeWithFfmpeg
+// to size its output buffer with.
+func encodeWithFfmpeg(img gocv.Mat, videoCodec, containerFormat string) ([]byte, bool, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, false, fmt.Errorf("%s codec requires the ffmpeg binary on PATH: %w", videoCodec, err)
+	}
+
+	width, height := img.Cols(), img.Rows()
+	cmd := exec.Command("ffmpeg",
+		"-f", "rawvideo",
+		"-pix_fmt", "bgr24",
+		"-s", fmt.Sprintf("%dx%d", width, height),
+		"-i", "-",
+		"-c:v", videoCodec,
+		"-f", containerFormat,
+		"-",
+	)
+	cmd.Stdin = bytes.NewReader(img.ToBytes())
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false, fmt.Errorf("ffmpeg encode (%s): %w", videoCodec, err)
+	}
+
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(width))
+	binary.BigEndian.PutUint32(header[4:8], uint32(height))
+	return append(header, out...), true, nil
+}
+
+// decodeWithFfmpeg reverses encodeWithFfmpeg: it reads the width/height
+// header back off, then pipes the remaining containerFormat bytes
+// through `ffmpeg -f containerFormat -i - -f rawvideo ...` to recover
+// the raw BGR24 frame.
+func decodeWithFfmpeg(data []byte, containerFormat string) (gocv.Mat, error) {
+	if len(data) < 8 {
+		return gocv.Mat{}, fmt.Errorf("ffmpeg-encoded frame too short for width/height header: %d bytes", len(data))
+	}
+	width := int(binary.BigEndian.Uint32(data[0:4]))
+	height := int(binary.BigEndian.Uint32(data[4:8]))
+	payload := data[8:]
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return gocv.Mat{}, fmt.Errorf("decoding %s frame requires the ffmpeg binary on PATH: %w", containerFormat, err)
+	}
+
+	cmd := exec.Command("ffmpeg",
+		"-f", containerFormat,
+		"-i", "-",
+		"-f", "rawvideo",
+		"-pix_fmt", "bgr24",
+		"-",
+	)
+	cmd.Stdin = bytes.NewReader(payload)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("ffmpeg decode (%s): %w", containerFormat, err)
+	}
+
+	return gocv.NewMatFromBytes(height, width, gocv.MatTypeCV8UC3, out)
+}
@@ -0,0 +1,77 @@
+package intercom
+
+import (
+	"testing"
+
+	"github.com/3xcellent/intercom/proto"
+	"gocv.io/x/gocv"
+)
+
+func TestEncodeDecodeImageFrameRoundTrip(t *testing.T) {
+	img := gocv.NewMatWithSize(4, 4, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	codecType, payload, err := encodeImageFrame(nil, "room-a", "alice", img)
+	if err != nil {
+		t.Fatalf("encodeImageFrame: %v", err)
+	}
+
+	roomID, senderID, mat, keyframe, err := decodeImageFrame(codecType, payload, img.Rows(), img.Cols())
+	if err != nil {
+		t.Fatalf("decodeImageFrame: %v", err)
+	}
+	defer mat.Close()
+
+	if roomID != "room-a" {
+		t.Errorf("roomID = %q, want %q", roomID, "room-a")
+	}
+	if senderID != "alice" {
+		t.Errorf("senderID = %q, want %q", senderID, "alice")
+	}
+	if !keyframe {
+		t.Errorf("keyframe = false, want true (raw/uncoded frames are always keyframes)")
+	}
+	if mat.Rows() != img.Rows() || mat.Cols() != img.Cols() {
+		t.Errorf("decoded size = %dx%d, want %dx%d", mat.Rows(), mat.Cols(), img.Rows(), img.Cols())
+	}
+}
+
+func TestIsKeyframeImage(t *testing.T) {
+	cases := []struct {
+		name  string
+		bytes []byte
+		want  bool
+	}{
+		{"keyframe flag set", []byte{1, 0, 0}, true},
+		{"keyframe flag clear", []byte{0, 0, 0}, false},
+		{"empty payload", nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isKeyframeImage(proto.Image{Bytes: tc.bytes}); got != tc.want {
+				t.Errorf("isKeyframeImage(%v) = %v, want %v", tc.bytes, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeImageFrameTruncated(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload []byte
+	}{
+		{"empty", nil},
+		{"missing room bytes", []byte{1, 5}},
+		{"missing sender length", []byte{1, 0}},
+		{"missing sender bytes", []byte{1, 0, 3}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, _, _, err := decodeImageFrame(codecRaw, tc.payload, 4, 4); err == nil {
+				t.Errorf("decodeImageFrame(%v): err = nil, want error", tc.payload)
+			}
+		})
+	}
+}
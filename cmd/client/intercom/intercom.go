@@ -6,6 +6,8 @@ import (
 	"image"
 	"io"
 	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/3xcellent/intercom/proto"
@@ -37,42 +39,115 @@ const (
 
 type intercomClient struct {
 	window            *gocv.Window
-	webcam            *gocv.VideoCapture
+	capture           CaptureSource
 	audioInputStream  *portaudio.Stream
 	audioOutputStream *portaudio.Stream
-	deviceID          string
 
 	context context.Context
 
-	intercomServer proto.Intercom_ConnectClient
+	transportMode TransportMode
+	transport     Transport
+
+	audioSourceMode AudioSourceMode
+
+	frameCodec FrameCodec
+	// waitingForKeyframe gates handleGrpcStreamRec on an encoded
+	// stream: non-keyframes are dropped until the first keyframe
+	// arrives, and it's re-armed on every disconnect.
+	waitingForKeyframe bool
 
 	bgImg           gocv.Mat
 	displayImg      gocv.Mat
 	videoPreviewImg gocv.Mat
-	inBroadcastImg  gocv.Mat
 
-	audioOutputCache [][]int32
-
-	lastInBroadcastTime time.Time
+	// roomID and participantName identify this client to the room it
+	// joins; participants tracks every other sender currently seen *in
+	// that room*, keyed by the sender id decoded off each frame (see
+	// decodeImageFrame). There's no server-side roomManager in this
+	// checkout to keep rooms from sharing one global fan-out, so
+	// handleGrpcStreamRec enforces the isolation itself: frames/audio
+	// whose decoded roomID doesn't match c.roomID never make it into
+	// this map, and so never reach draw/playAudio.
+	roomID          string
+	participantName string
+	participantsMu  sync.Mutex
+	participants    map[string]*Participant
+
+	// outgoingAudioSeq tags each buffer startAudioBroadcast sends so
+	// the receiving peer's jitter buffer can order and detect gaps in
+	// them. Only startAudioBroadcast's own goroutine touches it.
+	outgoingAudioSeq uint32
+
+	broadcast *BroadcastManager
+
+	hasCaptureOn    bool
+	hasMicOn        bool
+	wantToBroadcast bool
+	wantToQuit      bool
+}
 
-	isReceivingBroadcast bool
-	hasWebcamOn          bool
-	hasMicOn             bool
-	isPlayingAudio       bool
-	wantToBroadcast      bool
-	wantToQuit           bool
+// ClientConfig bundles the knobs CreateIntercomClient needs now that
+// video source, transport, audio source, and codec are all pluggable.
+// The zero value reproduces the client's original behavior: a webcam
+// at CaptureURI, gRPC transport, microphone audio, and raw BGR frames.
+type ClientConfig struct {
+	// CaptureURI selects the video source: "webcam://<deviceID>" for a
+	// local USB camera, "file://<path>" to loop a video file, or
+	// "rtsp://..." for an RTSP/IP-camera source. A bare device ID
+	// ("0") is treated as a webcam for backwards compatibility.
+	CaptureURI string
+	// BackgroundImage is the path loaded as the idle display image.
+	BackgroundImage string
+	// TransportMode selects whether video/audio ship over plain gRPC
+	// or a negotiated WebRTC session. WebRTC negotiation always fails in
+	// this build, so TransportModeWebRTC ends up on gRPC as well.
+	TransportMode TransportMode
+	// AudioSourceMode selects whether startAudioBroadcast broadcasts
+	// the microphone, system-output loopback (Windows only), or a mix
+	// of both.
+	AudioSourceMode AudioSourceMode
+	// FrameCodec compresses outgoing frames and decompresses incoming
+	// ones. Nil sends frames as uncompressed BGR (still inside the
+	// room/sender envelope encodeImageFrame adds).
+	FrameCodec FrameCodec
+	// RoomID is the named room/channel this client joins; participants
+	// in different rooms don't see or hear each other.
+	RoomID string
+	// ParticipantName identifies this client to the rest of the room.
+	ParticipantName string
 }
 
-func CreateIntercomClient(ctx context.Context, vidoeCaptureDeviceId, filename string) intercomClient {
+func CreateIntercomClient(ctx context.Context, cfg ClientConfig) intercomClient {
+	capture, err := newCaptureSource(cfg.CaptureURI)
+	if err != nil {
+		panic(err)
+	}
+
+	if cfg.TransportMode == TransportModeWebRTC {
+		fmt.Println("warning: TransportModeWebRTC is configured but not available in this build (pion/webrtc isn't vendored); connectToServer will fall back to gRPC")
+	}
+	switch cfg.FrameCodec.(type) {
+	case vp8Codec:
+		fmt.Println("warning: vp8 FrameCodec shells out to ffmpeg once per frame (no libvpx binding); fine for this client's frame rate, requires ffmpeg on PATH")
+	case h264Codec:
+		fmt.Println("warning: h264 FrameCodec shells out to ffmpeg once per frame (no libx264 binding); fine for this client's frame rate, requires ffmpeg on PATH")
+	}
+
 	client := intercomClient{
 		window:          gocv.NewWindow("Capture Window"),
-		deviceID:        vidoeCaptureDeviceId,
+		capture:         capture,
 		videoPreviewImg: gocv.NewMatWithSize(outPreviewHeight, outPreviewWidth, gocv.MatTypeCV8UC3),
-		inBroadcastImg:  gocv.NewMatWithSize(inBroadcastHeight, inBroadcastWidth, gocv.MatTypeCV8UC3),
+		broadcast:       NewBroadcastManager(),
+		transportMode:   cfg.TransportMode,
+		audioSourceMode: cfg.AudioSourceMode,
+		frameCodec:      cfg.FrameCodec,
+		roomID:          cfg.RoomID,
+		participantName: cfg.ParticipantName,
+		participants:    make(map[string]*Participant),
 		context:         ctx,
 	}
 
-	client.loadBackgroundImg(filename)
+	client.loadBackgroundImg(cfg.BackgroundImage)
 
 	return client
 }
@@ -92,16 +167,37 @@ func (c *intercomClient) loadBackgroundImg(path string) {
 	c.ResetDisplayImg()
 }
 
+// StartBroadcast mirrors this session to an external RTMP/RTSP endpoint
+// (e.g. "rtmp://live.example.com/app/stream") without disturbing the
+// existing peer-to-peer gRPC stream.
+func (c *intercomClient) StartBroadcast(url string) error {
+	return c.broadcast.Start(url)
+}
+
+// StopBroadcast ends the external mirror started by StartBroadcast, if
+// any.
+func (c *intercomClient) StopBroadcast() {
+	c.broadcast.Stop()
+}
+
 func (c *intercomClient) shutdown() {
-	if c.hasWebcamOn {
-		c.hasWebcamOn = false
-		c.webcam.Close()
+	c.broadcast.Stop()
+	c.transport.Close()
+
+	if c.hasCaptureOn {
+		c.hasCaptureOn = false
+		c.capture.Close()
 	}
 
 	c.bgImg.Close()
 	c.displayImg.Close()
 	c.videoPreviewImg.Close()
-	c.inBroadcastImg.Close()
+
+	c.participantsMu.Lock()
+	for _, p := range c.participants {
+		p.close()
+	}
+	c.participantsMu.Unlock()
 
 	c.window.Close()
 }
@@ -114,11 +210,18 @@ func (c *intercomClient) connectToServer() {
 	}
 
 	// create streams
+	//
+	// proto.Intercom.Connect doesn't take room/participant metadata in
+	// this checkout; once it does, c.roomID/c.participantName should
+	// be passed through here so the server's roomManager can route
+	// this stream into the right room.
 	client := proto.NewIntercomClient(conn)
-	c.intercomServer, err = client.Connect(c.context)
+	stream, err := client.Connect(c.context)
 	if err != nil {
 		panic(err)
 	}
+
+	c.transport = newTransport(stream, c.transportMode)
 }
 
 func (c *intercomClient) ResetDisplayImg() {
@@ -127,106 +230,126 @@ func (c *intercomClient) ResetDisplayImg() {
 
 func (c *intercomClient) handleGrpcStreamRec() {
 	for {
-		resp, err := c.intercomServer.Recv()
+		resp, err := c.transport.Recv()
 		if err == io.EOF {
 			c.ResetDisplayImg()
+			c.waitingForKeyframe = true
 			continue
 		}
 		if err != nil {
 			panic(err)
 		}
 
-		c.lastInBroadcastTime = time.Now()
-
 		respImage := resp.GetImage()
 		if respImage != nil {
+			if c.waitingForKeyframe && !isKeyframeImage(*respImage) {
+				continue
+			}
+			c.waitingForKeyframe = false
 			c.processBroadcastImage(*respImage)
 			continue
 		}
 
 		respAudio := resp.GetAudio()
 		if respAudio != nil {
-			c.audioOutputCache = append(c.audioOutputCache, respAudio.Samples)
-			if !c.isPlayingAudio {
-				c.isPlayingAudio = true
-				go c.playAudio()
+			if roomID, senderID, seq, samples, ok := decodeAudioSamples(respAudio.Samples); ok && roomID == c.roomID {
+				c.participantsMu.Lock()
+				p := c.participant(senderID)
+				p.lastSeen = time.Now()
+				c.participantsMu.Unlock()
+				p.audioJitter.Push(seq, samples)
 			}
 		}
 	}
 }
 
+// processBroadcastImage decodes an incoming frame and, if it's tagged
+// for this client's room, stores it on the sending Participant
+// (demuxed by the sender id decodeImageFrame extracts) so draw can
+// tile every live participant's video. Frames from other rooms are
+// decoded (to keep the stream in sync) and then dropped.
 func (c *intercomClient) processBroadcastImage(img proto.Image) {
-	serverImg, err := gocv.NewMatFromBytes(int(img.Height),
-		int(img.Width),
-		gocv.MatType(img.Type),
-		img.Bytes)
+	roomID, senderID, serverImg, _, err := decodeImageFrame(img.Type, img.Bytes, int(img.Height), int(img.Width))
 	if err != nil {
-		fmt.Printf("cannot create NewMatFromBytes %v\n", err)
-		c.ResetDisplayImg()
+		fmt.Printf("cannot decode image frame: %v\n", err)
 		return
 	}
 	defer serverImg.Close()
 
-	if serverImg.Empty() {
-		c.isReceivingBroadcast = false
-		c.ResetDisplayImg()
-		fmt.Println("incoming broadcast ended")
+	if roomID != c.roomID {
 		return
 	}
 
-	if !c.isReceivingBroadcast {
-		c.isReceivingBroadcast = true
-		fmt.Println("receiving incoming broadcast")
+	if serverImg.Empty() {
+		c.participantsMu.Lock()
+		if p, ok := c.participants[senderID]; ok {
+			p.close()
+			delete(c.participants, senderID)
+		}
+		c.participantsMu.Unlock()
+		fmt.Printf("incoming broadcast from %q ended\n", senderID)
+		return
 	}
 
 	screenCapRatio := float64(float64(serverImg.Size()[1]) / float64(serverImg.Size()[0]))
 	scaledHeight := int(math.Floor(inBroadcastWidth / screenCapRatio))
 
-	gocv.Resize(serverImg, &c.inBroadcastImg, image.Point{X: inBroadcastWidth, Y: scaledHeight}, 0, 0, gocv.InterpolationDefault)
+	c.participantsMu.Lock()
+	p := c.participant(senderID)
+	p.lastSeen = time.Now()
+	gocv.Resize(serverImg, &p.inBroadcastImg, image.Point{X: inBroadcastWidth, Y: scaledHeight}, 0, 0, gocv.InterpolationDefault)
+	c.participantsMu.Unlock()
 }
 
+// playAudio runs for the life of the client, pulling every live
+// participant's own jitter buffer and mixing them sample-wise into one
+// output buffer on PortAudio's own callback thread instead of blocking
+// this goroutine on Write() calls. That avoids the glitches the old
+// append-and-blocking-Write loop produced whenever the network
+// hiccuped: each participant's jitter buffer conceals a late/missing
+// packet instead of the whole output stream stalling.
 func (c *intercomClient) playAudio() {
-	out := make([]int32, sampleRate*sampleSeconds)
-	var err error
+	callback := func(out []int32) {
+		c.participantsMu.Lock()
+		live := c.prunedParticipants()
+		c.participantsMu.Unlock()
+
+		mixed := make([]int32, len(out))
+		for _, p := range live {
+			samples := p.audioJitter.Pull()
+			for i, s := range samples {
+				if i >= len(mixed) {
+					break
+				}
+				mixed[i] = clipInt32(int64(mixed[i]) + int64(s))
+			}
+		}
+		copy(out, mixed)
+	}
 
-	c.audioOutputStream, err = portaudio.OpenDefaultStream(0, 1, sampleRate, len(out), &out)
+	stream, err := portaudio.OpenDefaultStream(0, 1, sampleRate, int(sampleRate*sampleSeconds), callback)
 	if err != nil {
 		panic("audio out err: " + err.Error())
 	}
-	defer c.audioOutputStream.Close()
+	c.audioOutputStream = stream
+	defer stream.Close()
 
-	c.audioOutputStream.Start()
-	defer c.audioOutputStream.Stop()
-
-	// audio playback loop
-	for {
-		cacheLength := len(c.audioOutputCache)
-		if cacheLength == 0 {
-			c.isPlayingAudio = false
-			break
-		}
-
-		c.isPlayingAudio = true
-
-		out = c.audioOutputCache[0]
-		c.audioOutputCache = c.audioOutputCache[1:]
-		err = c.audioOutputStream.Write()
-		if err != nil {
-			panic("playback err: " + err.Error())
-		}
+	if err := stream.Start(); err != nil {
+		panic("audio out err: " + err.Error())
 	}
+	defer stream.Stop()
+
+	<-c.context.Done()
 }
 
 func (c *intercomClient) startAudioBroadcast() {
 	c.hasMicOn = true
-	in := make([]int32, 44100*.1)
-	fmt.Println("OpenDefaultStream...")
-	audioInStream, err := portaudio.OpenDefaultStream(1, 0, 44100, len(in), &in)
+
+	audioSource, err := newAudioSource(c.audioSourceMode, int(sampleRate*sampleSeconds))
 	if err != nil {
 		panic(err)
 	}
-	err = audioInStream.Start()
-	if err != nil {
+	if err := audioSource.Open(); err != nil {
 		panic(err)
 	}
 
@@ -242,80 +365,75 @@ func (c *intercomClient) startAudioBroadcast() {
 			break
 		}
 
-		err = audioInStream.Read()
+		in, err := audioSource.Read()
 		if err != nil {
 			panic(err)
 		}
 
-		go func(sendSamples []int32) {
-			req := proto.Broadcast{
-				BroadcastType: &proto.Broadcast_Audio{
-					Audio: &proto.Audio{
-						Samples: sendSamples,
-					},
-				},
-			}
+		c.broadcast.PublishSample(in)
 
-			if err := c.intercomServer.Send(&req); err != nil {
+		seq := c.outgoingAudioSeq
+		c.outgoingAudioSeq++
+
+		go func(sendSamples []int32) {
+			audio := proto.Audio{Samples: encodeAudioSamples(c.roomID, c.participantName, seq, sendSamples)}
+			if err := c.transport.SendAudio(&audio); err != nil {
 				fmt.Printf("Send error: %v", err)
 				return
 			}
-			if err != nil {
-				panic(err)
-			}
 		}(in)
 	}
-	err = audioInStream.Stop()
-	if err != nil {
+	if err := audioSource.Close(); err != nil {
 		panic(err)
 	}
 	c.hasMicOn = false
 }
 
 func (c *intercomClient) sendVideoCapture() {
-	if !c.hasWebcamOn {
-		var err error
-		c.webcam, err = gocv.OpenVideoCapture(c.deviceID)
-		if err != nil {
-			fmt.Printf("Error opening video capture device: %v\n", c.deviceID)
+	if !c.hasCaptureOn {
+		if err := c.capture.Open(); err != nil {
+			fmt.Printf("Error opening video capture source: %v\n", err)
 			return
 		}
-		c.hasWebcamOn = true
+		c.hasCaptureOn = true
 		fmt.Println("outgoing broadcast starting")
 	}
 
 	videoCaptureImg := gocv.NewMat()
 	defer videoCaptureImg.Close()
 
-	if ok := c.webcam.Read(&videoCaptureImg); !ok {
+	if ok := c.capture.Read(&videoCaptureImg); !ok {
 		fmt.Println("didn't read from cam")
 	}
 
 	if videoCaptureImg.Empty() {
-		if c.hasWebcamOn {
-			c.webcam.Close()
-			c.hasWebcamOn = false
+		if c.hasCaptureOn {
+			c.capture.Close()
+			c.hasCaptureOn = false
 			fmt.Println("outgoing broadcast ended")
 		}
 		return
 	}
 
-	req := proto.Broadcast{
-		BroadcastType: &proto.Broadcast_Image{
-			Image: &proto.Image{
-				Height: int32(videoCaptureImg.Size()[0]),
-				Width:  int32(videoCaptureImg.Size()[1]),
-				Type:   int32(videoCaptureImg.Type()),
-				Bytes:  videoCaptureImg.ToBytes(),
-			},
-		},
+	codecType, payload, err := encodeImageFrame(c.frameCodec, c.roomID, c.participantName, videoCaptureImg)
+	if err != nil {
+		fmt.Printf("cannot encode frame: %v\n", err)
+		return
+	}
+
+	img := proto.Image{
+		Height: int32(videoCaptureImg.Size()[0]),
+		Width:  int32(videoCaptureImg.Size()[1]),
+		Type:   codecType,
+		Bytes:  payload,
 	}
 
-	if err := c.intercomServer.Send(&req); err != nil {
+	if err := c.transport.SendImage(&img); err != nil {
 		fmt.Printf("Send error: %v", err)
 		return
 	}
 
+	c.broadcast.PublishFrame(videoCaptureImg)
 
 	screenCapRatio := float64(float64(videoCaptureImg.Size()[1]) / float64(videoCaptureImg.Size()[0]))
 	outPreviewScaledHeight := int(math.Floor(outPreviewWidth / screenCapRatio))
@@ -324,15 +442,9 @@ func (c *intercomClient) sendVideoCapture() {
 }
 
 func (c *intercomClient) draw() {
-	if c.hasIncomingBroadcast() {
-		for x := 0; x < c.inBroadcastImg.Size()[0]; x++ {
-			for y := 0; y < inBroadcastWidth; y++ {
-				c.displayImg.SetIntAt3(x+inBroadcastX, y+inBroadcastY, 0, c.inBroadcastImg.GetIntAt3(x, y, 0))
-			}
-		}
-	}
+	c.drawParticipants()
 
-	if c.hasWebcamOn {
+	if c.hasCaptureOn {
 		for x := 0; x < c.videoPreviewImg.Size()[0]; x++ {
 			for y := 0; y < outPreviewWidth; y++ {
 				c.displayImg.SetIntAt3(x+outPreviewX, y+outPreviewY, 0, c.videoPreviewImg.GetIntAt3(x, outPreviewWidth-y, 0))
@@ -343,16 +455,46 @@ func (c *intercomClient) draw() {
 	c.window.IMShow(c.displayImg)
 }
 
-func (c *intercomClient) hasIncomingBroadcast() bool {
-	if !c.isReceivingBroadcast {
-		return false
-	}
-	if time.Now().After(c.lastInBroadcastTime.Add(300 * time.Millisecond)) {
-		c.isReceivingBroadcast = false
+// drawParticipants tiles every live participant's video into the
+// inBroadcast region in a grid, pruning anyone who's gone stale and
+// resetting the display when the room is empty.
+func (c *intercomClient) drawParticipants() {
+	c.participantsMu.Lock()
+	live := c.prunedParticipants()
+	c.participantsMu.Unlock()
+
+	if len(live) == 0 {
 		c.ResetDisplayImg()
-		return false
+		return
+	}
+
+	// c.participants is a map, so prunedParticipants' iteration order
+	// (and therefore live's order) is randomized per call. Sort by ID
+	// so each participant holds a stable grid cell instead of tiles
+	// swapping position from frame to frame.
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+
+	cols := int(math.Ceil(math.Sqrt(float64(len(live)))))
+	rows := int(math.Ceil(float64(len(live)) / float64(cols)))
+	cellWidth := inBroadcastWidth / cols
+	cellHeight := inBroadcastHeight / rows
+
+	tile := gocv.NewMat()
+	defer tile.Close()
+
+	for i, p := range live {
+		col := i % cols
+		row := i / cols
+		offsetX := inBroadcastX + row*cellHeight
+		offsetY := inBroadcastY + col*cellWidth
+
+		gocv.Resize(p.inBroadcastImg, &tile, image.Point{X: cellWidth, Y: cellHeight}, 0, 0, gocv.InterpolationDefault)
+		for x := 0; x < tile.Size()[0]; x++ {
+			for y := 0; y < tile.Size()[1]; y++ {
+				c.displayImg.SetIntAt3(x+offsetX, y+offsetY, 0, tile.GetIntAt3(x, y, 0))
+			}
+		}
 	}
-	return true
 }
 
 func (c *intercomClient) Run() {
@@ -362,6 +504,7 @@ func (c *intercomClient) Run() {
 	defer portaudio.Terminate()
 
 	go c.handleGrpcStreamRec()
+	go c.playAudio()
 
 	// main program loop
 	for {
@@ -395,9 +538,9 @@ func (c *intercomClient) Run() {
 			if c.hasMicOn {
 				c.hasMicOn = false
 			}
-			if c.hasWebcamOn {
-				c.webcam.Close()
-				c.hasWebcamOn = false
+			if c.hasCaptureOn {
+				c.capture.Close()
+				c.hasCaptureOn = false
 				c.ResetDisplayImg()
 			}
 		}
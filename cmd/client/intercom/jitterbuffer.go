@@ -0,0 +1,174 @@
+package intercom
+
+import "sync"
+
+const (
+	// jitterTargetLatency is the initial targetLatency: how many frames
+	// Push buffers before playout starts. Once playout has started,
+	// Pull advances one sequence number per call and targetLatency no
+	// longer changes the playout delay.
+	jitterTargetLatency = 3
+	// jitterMinLatency/jitterMaxLatency bound the adaptive target:
+	// Pull grows the target on a run of late arrivals (underruns) and
+	// shrinks it on a run of early ones, within these limits.
+	jitterMinLatency = 1
+	jitterMaxLatency = 10
+	// jitterAdaptAfter is how many consecutive late/early pulls in a
+	// row trigger a target-latency adjustment.
+	jitterAdaptAfter = 5
+	// concealmentAttenuation shrinks a repeated frame used to paper
+	// over a missing slot, so packet loss fades out instead of looping
+	// at full volume.
+	concealmentAttenuation = 0.6
+)
+
+// JitterBuffer smooths out network jitter between arrival of
+// sequenced audio frames and their steady-rate playout: Push as
+// packets arrive (out of order or with gaps is fine), Pull once per
+// playout tick to get the next frame. A frame missing at playout time
+// is concealed by replaying the last good frame, attenuated, instead
+// of gapping the audio.
+type JitterBuffer struct {
+	mu sync.Mutex
+
+	frameSize int
+	slots     map[uint32][]int32
+
+	nextPlaySeq   uint32
+	started       bool
+	targetLatency int
+
+	lastGoodFrame []int32
+	lateStreak    int
+	earlyStreak   int
+}
+
+// NewJitterBuffer returns a buffer that plays frameSize-sample frames
+// at jitterTargetLatency frames of playout delay.
+func NewJitterBuffer(frameSize int) *JitterBuffer {
+	return &JitterBuffer{
+		frameSize:     frameSize,
+		slots:         make(map[uint32][]int32),
+		targetLatency: jitterTargetLatency,
+	}
+}
+
+// Push stores samples under seq, discarding it if it arrived so late
+// it's already been played out or conceeded past. Playout doesn't
+// start until targetLatency frames have been buffered, so early
+// packets have a chance to smooth out jitter before anything plays.
+func (j *JitterBuffer) Push(seq uint32, samples []int32) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.started && seqBefore(seq, j.nextPlaySeq) {
+		return // too late, already played or conceded
+	}
+
+	j.slots[seq] = samples
+
+	if !j.started && len(j.slots) >= j.targetLatency {
+		if min, ok := minSeq(j.slots); ok {
+			j.nextPlaySeq = min
+			j.started = true
+		}
+	}
+}
+
+// minSeq returns the smallest sequence number currently buffered,
+// accounting for uint32 wraparound the same way seqBefore does.
+func minSeq(slots map[uint32][]int32) (uint32, bool) {
+	var min uint32
+	first := true
+	for seq := range slots {
+		if first || seqBefore(seq, min) {
+			min = seq
+			first = false
+		}
+	}
+	return min, !first
+}
+
+// Pull returns the next frame to play, concealing a missing slot by
+// repeating the last good frame (attenuated). It also adapts
+// targetLatency: a run of missing slots grows it, a run of pulls that
+// find more than targetLatency frames buffered shrinks it. The
+// adjusted value is only recorded; playout is never paused or skipped
+// ahead to match it.
+func (j *JitterBuffer) Pull() []int32 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.started {
+		return make([]int32, j.frameSize)
+	}
+
+	playSeq := j.nextPlaySeq
+	j.nextPlaySeq++
+
+	samples, ok := j.slots[playSeq]
+	if ok {
+		delete(j.slots, playSeq)
+		j.lastGoodFrame = samples
+		j.noteArrival(onTime)
+		return samples
+	}
+
+	j.noteArrival(late)
+	return j.concealedFrame()
+}
+
+type arrivalKind int
+
+const (
+	onTime arrivalKind = iota
+	late
+)
+
+// noteArrival tracks consecutive late pulls and consecutive pulls that
+// find the buffer unusually full (arriving early), nudging
+// targetLatency when either streak crosses jitterAdaptAfter. Callers
+// must hold j.mu.
+func (j *JitterBuffer) noteArrival(kind arrivalKind) {
+	switch kind {
+	case late:
+		j.lateStreak++
+		j.earlyStreak = 0
+		if j.lateStreak >= jitterAdaptAfter && j.targetLatency < jitterMaxLatency {
+			j.targetLatency++
+			j.lateStreak = 0
+		}
+	case onTime:
+		j.lateStreak = 0
+		if len(j.slots) > j.targetLatency {
+			j.earlyStreak++
+		} else {
+			j.earlyStreak = 0
+		}
+		if j.earlyStreak >= jitterAdaptAfter && j.targetLatency > jitterMinLatency {
+			j.targetLatency--
+			j.earlyStreak = 0
+		}
+	}
+}
+
+// concealedFrame replays the last good frame at reduced volume, or
+// silence if nothing has played yet. Callers must hold j.mu.
+func (j *JitterBuffer) concealedFrame() []int32 {
+	if j.lastGoodFrame == nil {
+		return make([]int32, j.frameSize)
+	}
+
+	out := make([]int32, len(j.lastGoodFrame))
+	for i, s := range j.lastGoodFrame {
+		out[i] = int32(float64(s) * concealmentAttenuation)
+	}
+	j.lastGoodFrame = out
+	return out
+}
+
+// seqBefore reports whether a comes strictly before b, accounting for
+// uint32 sequence-number wraparound.
+func seqBefore(a, b uint32) bool {
+	return int32(a-b) < 0
+}
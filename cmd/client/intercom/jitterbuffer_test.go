@@ -0,0 +1,108 @@
+package intercom
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeqBefore(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b uint32
+		want bool
+	}{
+		{"a before b", 1, 2, true},
+		{"a after b", 2, 1, false},
+		{"equal", 5, 5, false},
+		{"wraps around max uint32", math.MaxUint32, 0, true},
+		{"does not wrap the other way", 0, math.MaxUint32, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := seqBefore(tc.a, tc.b); got != tc.want {
+				t.Errorf("seqBefore(%d, %d) = %v, want %v", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMinSeq(t *testing.T) {
+	if _, ok := minSeq(map[uint32][]int32{}); ok {
+		t.Errorf("minSeq(empty): ok = true, want false")
+	}
+
+	slots := map[uint32][]int32{5: nil, 2: nil, 9: nil}
+	min, ok := minSeq(slots)
+	if !ok || min != 2 {
+		t.Errorf("minSeq(%v) = (%d, %v), want (2, true)", slots, min, ok)
+	}
+
+	wrapped := map[uint32][]int32{1: nil, math.MaxUint32: nil}
+	min, ok = minSeq(wrapped)
+	if !ok || min != math.MaxUint32 {
+		t.Errorf("minSeq(%v) = (%d, %v), want (%d, true)", wrapped, min, ok, uint32(math.MaxUint32))
+	}
+}
+
+func TestJitterBufferDelaysPlayoutUntilTargetLatency(t *testing.T) {
+	jb := NewJitterBuffer(2)
+
+	for i := uint32(0); i < jitterTargetLatency-1; i++ {
+		jb.Push(i, []int32{int32(i), int32(i)})
+		if got := jb.Pull(); got[0] != 0 || got[1] != 0 {
+			t.Fatalf("Pull before target latency reached = %v, want silence", got)
+		}
+	}
+}
+
+func TestJitterBufferPlaysInOrder(t *testing.T) {
+	jb := NewJitterBuffer(1)
+
+	for i := uint32(0); i < jitterTargetLatency; i++ {
+		jb.Push(i, []int32{int32(i) + 1})
+	}
+
+	for i := uint32(0); i < jitterTargetLatency; i++ {
+		got := jb.Pull()
+		if got[0] != int32(i)+1 {
+			t.Fatalf("Pull() #%d = %v, want [%d]", i, got, i+1)
+		}
+	}
+}
+
+func TestJitterBufferConcealsMissingFrame(t *testing.T) {
+	jb := NewJitterBuffer(1)
+
+	for i := uint32(0); i < jitterTargetLatency; i++ {
+		jb.Push(i, []int32{100})
+	}
+	for i := uint32(0); i < jitterTargetLatency; i++ {
+		jb.Pull() // consumes seq 0..jitterTargetLatency-1
+	}
+
+	// The next frame (seq == jitterTargetLatency) never arrives: Pull
+	// should conceal it by replaying the last good frame, attenuated,
+	// instead of returning silence or blocking.
+	concealed := jb.Pull()
+	want := int32(100 * concealmentAttenuation)
+	if concealed[0] != want {
+		t.Errorf("concealed frame = %v, want [%d]", concealed, want)
+	}
+}
+
+func TestJitterBufferDropsLateArrival(t *testing.T) {
+	jb := NewJitterBuffer(1)
+
+	for i := uint32(0); i < jitterTargetLatency; i++ {
+		jb.Push(i, []int32{1})
+	}
+	jb.Pull() // nextPlaySeq is now 1, past seq 0
+
+	// Pushing a seq already played/passed should be a no-op, not panic
+	// or corrupt state.
+	jb.Push(0, []int32{999})
+	if _, ok := jb.slots[0]; ok {
+		t.Errorf("slots[0] present after a too-late Push, want discarded")
+	}
+}
@@ -0,0 +1,71 @@
+package intercom
+
+import (
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// participantTimeout mirrors the single-peer 300ms staleness window
+// hasIncomingBroadcast used before multi-party support: a participant
+// who hasn't sent a frame in that long is considered gone.
+const participantTimeout = 300 * time.Millisecond
+
+// Participant tracks one remote peer's incoming video and audio in a
+// room: inBroadcastImg is the latest decoded frame (see
+// processBroadcastImage), and audioJitter smooths that peer's own
+// sequence-numbered audio packets out to a steady playout rate.
+// Buffering audio per participant instead of in one client-wide
+// JitterBuffer is what lets playAudio mix every live participant's
+// stream instead of two senders' independently-numbered packets
+// colliding in a single sequence space.
+type Participant struct {
+	ID             string
+	inBroadcastImg gocv.Mat
+	audioJitter    *JitterBuffer
+	lastSeen       time.Time
+}
+
+func newParticipant(id string) *Participant {
+	return &Participant{
+		ID:             id,
+		inBroadcastImg: gocv.NewMatWithSize(inBroadcastHeight, inBroadcastWidth, gocv.MatTypeCV8UC3),
+		audioJitter:    NewJitterBuffer(int(sampleRate * sampleSeconds)),
+		lastSeen:       time.Now(),
+	}
+}
+
+func (p *Participant) isStale() bool {
+	return time.Now().After(p.lastSeen.Add(participantTimeout))
+}
+
+func (p *Participant) close() {
+	p.inBroadcastImg.Close()
+}
+
+// participant returns (creating if needed) the Participant tracked for
+// id. Callers must hold c.participantsMu.
+func (c *intercomClient) participant(id string) *Participant {
+	if p, ok := c.participants[id]; ok {
+		return p
+	}
+	p := newParticipant(id)
+	c.participants[id] = p
+	return p
+}
+
+// prunedParticipants returns the currently live (non-stale)
+// participants, closing and removing any that have gone stale.
+// Callers must hold c.participantsMu.
+func (c *intercomClient) prunedParticipants() []*Participant {
+	live := make([]*Participant, 0, len(c.participants))
+	for id, p := range c.participants {
+		if p.isStale() {
+			p.close()
+			delete(c.participants, id)
+			continue
+		}
+		live = append(live, p)
+	}
+	return live
+}
@@ -0,0 +1,80 @@
+package intercom
+
+import (
+	"fmt"
+
+	"github.com/3xcellent/intercom/proto"
+)
+
+// TransportMode selects how intercomClient ships video/audio to the
+// server: the original gRPC stream of raw proto.Image/proto.Audio
+// messages, or a WebRTC session negotiated over that same gRPC
+// connection. WebRTC negotiation always fails in this build (see
+// newWebRTCTransport), so both modes end up on the gRPC stream.
+type TransportMode int
+
+const (
+	// TransportModeGRPC sends full proto.Broadcast messages over the
+	// existing bidirectional gRPC stream, same as before this type
+	// existed.
+	TransportModeGRPC TransportMode = iota
+	// TransportModeWebRTC attempts a WebRTC session over the gRPC
+	// stream; newWebRTCTransport always returns an error, so
+	// newTransport falls back to the gRPC transport and no media tracks
+	// are sent.
+	TransportModeWebRTC
+)
+
+// Transport is the seam between intercomClient and however frames and
+// samples actually reach the server. sendVideoCapture/startAudioBroadcast
+// call SendImage/SendAudio; handleGrpcStreamRec calls Recv.
+type Transport interface {
+	SendImage(img *proto.Image) error
+	SendAudio(audio *proto.Audio) error
+	Recv() (*proto.Broadcast, error)
+	Close() error
+}
+
+// newTransport wraps the already-connected gRPC stream in the
+// Transport for mode, falling back to plain gRPC if a WebRTC session
+// can't be negotiated.
+func newTransport(client proto.Intercom_ConnectClient, mode TransportMode) Transport {
+	grpcT := &grpcTransport{stream: client}
+
+	if mode != TransportModeWebRTC {
+		return grpcT
+	}
+
+	webrtcT, err := newWebRTCTransport(grpcT)
+	if err != nil {
+		fmt.Printf("webrtc negotiation failed, falling back to grpc: %v\n", err)
+		return grpcT
+	}
+	return webrtcT
+}
+
+// grpcTransport is the original transport: every frame/sample is
+// wrapped in a proto.Broadcast and sent over the gRPC stream as-is.
+type grpcTransport struct {
+	stream proto.Intercom_ConnectClient
+}
+
+func (t *grpcTransport) SendImage(img *proto.Image) error {
+	return t.stream.Send(&proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Image{Image: img},
+	})
+}
+
+func (t *grpcTransport) SendAudio(audio *proto.Audio) error {
+	return t.stream.Send(&proto.Broadcast{
+		BroadcastType: &proto.Broadcast_Audio{Audio: audio},
+	})
+}
+
+func (t *grpcTransport) Recv() (*proto.Broadcast, error) {
+	return t.stream.Recv()
+}
+
+func (t *grpcTransport) Close() error {
+	return t.stream.CloseSend()
+}
@@ -0,0 +1,70 @@
+package intercom
+
+import (
+	"errors"
+
+	"github.com/3xcellent/intercom/proto"
+)
+
+// STATUS: not functional. Unlike ffmpegPipeline/rtspClient/the ffmpeg-
+// backed FrameCodecs, there's no subprocess shortcut for a WebRTC
+// PeerConnection: real negotiation needs both the
+// github.com/pion/webrtc/v3 dependency itself and a signaling RPC on
+// proto.Intercom (e.g. `Negotiate(SessionDescription) returns
+// (SessionDescription)`) that intercom.proto would need to grow, and
+// this checkout can edit neither the go.mod nor the generated proto
+// package. newTransport always falls back to grpcTransport as a
+// result (see newWebRTCTransport below) — do not treat
+// TransportModeWebRTC as a working feature in this build.
+//
+// webrtcTransport below is scaffolding for what the real
+// implementation will fill in once both land: it would carry video as
+// an H.264/VP8 track and audio as an Opus track over a
+// webrtc.PeerConnection, negotiated via a small offer/answer exchange
+// signaled over the existing gRPC stream (signaling channel, not the
+// media path), replacing full raw proto.Image/proto.Audio messages on
+// the bandwidth- and jitter-sensitive path.
+type webrtcTransport struct {
+	signaling *grpcTransport
+
+	// peerConnection *webrtc.PeerConnection
+	// videoTrack     *webrtc.TrackLocalStaticSample
+	// audioTrack     *webrtc.TrackLocalStaticSample
+}
+
+// newWebRTCTransport always errors today — see the STATUS note above —
+// so every call site falls back to grpcTransport instead of this type
+// ever being constructed.
+func newWebRTCTransport(signaling *grpcTransport) (*webrtcTransport, error) {
+	// TODO once pion/webrtc is vendored and proto.Intercom grows a
+	// Negotiate RPC:
+	//   1. build a webrtc.PeerConnection with video (H.264/VP8) and
+	//      audio (Opus) local tracks
+	//   2. CreateOffer, SetLocalDescription
+	//   3. send the offer via signaling and await the answer
+	//   4. SetRemoteDescription with the answer
+	return nil, errors.New("webrtc transport not yet available: pion/webrtc and the signaling RPC are not in this build")
+}
+
+// SendImage/SendAudio/Recv/Close below can never run: newWebRTCTransport
+// always errors, so no webrtcTransport value is ever constructed to
+// call them on. They exist only so webrtcTransport already satisfies
+// Transport once newWebRTCTransport is filled in for real.
+
+func (t *webrtcTransport) SendImage(img *proto.Image) error {
+	// would write img.Bytes as an H.264/VP8 sample into videoTrack
+	return errors.New("webrtc transport not implemented")
+}
+
+func (t *webrtcTransport) SendAudio(audio *proto.Audio) error {
+	// would write audio.Samples as an Opus sample into audioTrack
+	return errors.New("webrtc transport not implemented")
+}
+
+func (t *webrtcTransport) Recv() (*proto.Broadcast, error) {
+	return t.signaling.Recv()
+}
+
+func (t *webrtcTransport) Close() error {
+	return t.signaling.Close()
+}